@@ -0,0 +1,82 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package connectors
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the top-level shape of the connectors configuration file
+// loaded at Console startup, e.g.:
+//
+//	github:
+//	  clientID: xxxx
+//	  clientSecret: xxxx
+//	  redirectURL: https://console.example.com/auth/github/callback
+//	gitlab:
+//	  clientID: xxxx
+//	  clientSecret: xxxx
+//	  redirectURL: https://console.example.com/auth/gitlab/callback
+//	oauth2:
+//	  - id: my-idp
+//	    clientID: xxxx
+//	    clientSecret: xxxx
+//	    redirectURL: https://console.example.com/auth/my-idp/callback
+//	    authURL: https://idp.example.com/oauth2/authorize
+//	    tokenURL: https://idp.example.com/oauth2/token
+//	    userInfoURL: https://idp.example.com/oauth2/userinfo
+type Config struct {
+	GitHub *GitHubConfig  `yaml:"github"`
+	GitLab *GitLabConfig  `yaml:"gitlab"`
+	OAuth2 []OAuth2Config `yaml:"oauth2"`
+}
+
+// LoadConfig parses a connectors configuration file at path and builds
+// a Registry containing one Connector per configured entry.
+func LoadConfig(path string) (*Registry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("connectors: unable to read config %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("connectors: unable to parse config %s: %w", path, err)
+	}
+	return NewRegistryFromConfig(cfg)
+}
+
+// NewRegistryFromConfig builds a Registry from an already-parsed Config.
+func NewRegistryFromConfig(cfg Config) (*Registry, error) {
+	var conns []Connector
+	if cfg.GitHub != nil {
+		conns = append(conns, NewGitHubConnector(*cfg.GitHub))
+	}
+	if cfg.GitLab != nil {
+		conns = append(conns, NewGitLabConnector(*cfg.GitLab))
+	}
+	for _, oc := range cfg.OAuth2 {
+		if oc.ConnectorID == "" {
+			return nil, fmt.Errorf("connectors: oauth2 entry is missing an id")
+		}
+		conns = append(conns, NewOAuth2Connector(oc))
+	}
+	return NewRegistry(conns...), nil
+}
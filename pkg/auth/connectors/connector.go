@@ -0,0 +1,73 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package connectors provides a pluggable identity-connector interface
+// for the Console login path, together with built-in implementations
+// for GitHub, GitLab and a generic OAuth2 provider.
+package connectors
+
+import "net/http"
+
+// Identity is the normalized result of a successful login through any
+// Connector, regardless of which upstream provider produced it.
+type Identity struct {
+	// ConnectorID is the ID() of the Connector that produced this Identity.
+	ConnectorID string
+	// Subject is the provider's stable, unique identifier for the user.
+	Subject string
+	// Email is the user's primary, verified e-mail address.
+	Email string
+	// Username is the provider's human-readable login name, if any.
+	Username string
+}
+
+// Connector drives one identity provider's login flow: building the
+// URL the browser is redirected to, and turning the resulting callback
+// request into an Identity.
+type Connector interface {
+	// ID identifies this connector instance, e.g. "github" or a
+	// configured name for a generic OAuth2 provider. It is also the
+	// path segment mounted at /auth/{connector_id}/....
+	ID() string
+	// LoginURL returns the URL the user's browser should be redirected
+	// to in order to begin login, embedding the given anti-CSRF state.
+	LoginURL(state string) string
+	// HandleCallback validates the redirect callback request and
+	// exchanges its authorization code for the caller's Identity.
+	HandleCallback(r *http.Request) (Identity, error)
+}
+
+// Registry looks up configured connectors by ID.
+type Registry struct {
+	connectors map[string]Connector
+}
+
+// NewRegistry builds a Registry from a list of connectors, keyed by
+// each connector's ID(). Later connectors with a duplicate ID replace
+// earlier ones.
+func NewRegistry(conns ...Connector) *Registry {
+	r := &Registry{connectors: make(map[string]Connector, len(conns))}
+	for _, c := range conns {
+		r.connectors[c.ID()] = c
+	}
+	return r
+}
+
+// Get returns the connector registered under id, and whether it was found.
+func (r *Registry) Get(id string) (Connector, bool) {
+	c, ok := r.connectors[id]
+	return c, ok
+}
@@ -0,0 +1,213 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package connectors
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// RoundTripFunc lets a test stand in for the identity provider endpoints
+// a Connector talks to, without making any real network calls.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip .
+func (f RoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// withTestClient temporarily swaps the package-level httpClient for one
+// backed by fn, restoring the original client once the test is done.
+func withTestClient(t *testing.T, fn RoundTripFunc) {
+	t.Helper()
+	original := httpClient
+	httpClient = &http.Client{Transport: fn}
+	t.Cleanup(func() { httpClient = original })
+}
+
+func jsonResponse(body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+		Header:     make(http.Header),
+	}
+}
+
+func Test_githubConnector_HandleCallback(t *testing.T) {
+	withTestClient(t, func(req *http.Request) (*http.Response, error) {
+		switch {
+		case req.URL.String() == githubTokenURL:
+			return jsonResponse(`{"access_token":"tok-123"}`), nil
+		case req.URL.String() == githubUserAPI:
+			return jsonResponse(`{"id":42,"login":"octocat","email":"octocat@example.com"}`), nil
+		default:
+			t.Fatalf("unexpected request to %s", req.URL)
+			return nil, nil
+		}
+	})
+
+	c := NewGitHubConnector(GitHubConfig{ClientID: "id", ClientSecret: "secret", RedirectURL: "https://console.example.com/auth/github/callback"})
+	req := httptest.NewRequest(http.MethodGet, "/auth/github/callback?code=abc&state=xyz", nil)
+
+	identity, err := c.HandleCallback(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if identity.Subject != "42" || identity.Email != "octocat@example.com" || identity.Username != "octocat" {
+		t.Errorf("unexpected identity: %+v", identity)
+	}
+}
+
+func Test_githubConnector_HandleCallback_privateEmail(t *testing.T) {
+	withTestClient(t, func(req *http.Request) (*http.Response, error) {
+		switch {
+		case req.URL.String() == githubTokenURL:
+			return jsonResponse(`{"access_token":"tok-123"}`), nil
+		case req.URL.String() == githubUserAPI:
+			return jsonResponse(`{"id":42,"login":"octocat","email":""}`), nil
+		case req.URL.String() == githubEmailAPI:
+			return jsonResponse(`[{"email":"noreply@example.com","primary":false,"verified":true},{"email":"real@example.com","primary":true,"verified":true}]`), nil
+		default:
+			t.Fatalf("unexpected request to %s", req.URL)
+			return nil, nil
+		}
+	})
+
+	c := NewGitHubConnector(GitHubConfig{ClientID: "id", ClientSecret: "secret", RedirectURL: "https://console.example.com/auth/github/callback"})
+	req := httptest.NewRequest(http.MethodGet, "/auth/github/callback?code=abc&state=xyz", nil)
+
+	identity, err := c.HandleCallback(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if identity.Email != "real@example.com" {
+		t.Errorf("expected primary verified e-mail, got %q", identity.Email)
+	}
+}
+
+func Test_githubConnector_HandleCallback_missingCode(t *testing.T) {
+	c := NewGitHubConnector(GitHubConfig{})
+	req := httptest.NewRequest(http.MethodGet, "/auth/github/callback", nil)
+
+	if _, err := c.HandleCallback(req); err == nil {
+		t.Error("expected an error for a callback request missing the authorization code")
+	}
+}
+
+func Test_gitlabConnector_HandleCallback(t *testing.T) {
+	withTestClient(t, func(req *http.Request) (*http.Response, error) {
+		switch {
+		case req.URL.String() == gitlabTokenURL:
+			return jsonResponse(`{"access_token":"tok-123"}`), nil
+		case req.URL.String() == gitlabUserAPI:
+			return jsonResponse(`{"id":7,"username":"gitlabber","email":"gitlabber@example.com"}`), nil
+		default:
+			t.Fatalf("unexpected request to %s", req.URL)
+			return nil, nil
+		}
+	})
+
+	c := NewGitLabConnector(GitLabConfig{ClientID: "id", ClientSecret: "secret", RedirectURL: "https://console.example.com/auth/gitlab/callback"})
+	req := httptest.NewRequest(http.MethodGet, "/auth/gitlab/callback?code=abc&state=xyz", nil)
+
+	identity, err := c.HandleCallback(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if identity.Subject != "7" || identity.Email != "gitlabber@example.com" || identity.Username != "gitlabber" {
+		t.Errorf("unexpected identity: %+v", identity)
+	}
+}
+
+func Test_oauth2Connector_HandleCallback(t *testing.T) {
+	cfg := OAuth2Config{
+		ConnectorID: "my-idp",
+		ClientID:    "id",
+		AuthURL:     "https://idp.example.com/oauth2/authorize",
+		TokenURL:    "https://idp.example.com/oauth2/token",
+		UserInfoURL: "https://idp.example.com/oauth2/userinfo",
+	}
+	withTestClient(t, func(req *http.Request) (*http.Response, error) {
+		switch {
+		case req.URL.String() == cfg.TokenURL:
+			return jsonResponse(`{"access_token":"tok-123"}`), nil
+		case req.URL.String() == cfg.UserInfoURL:
+			return jsonResponse(`{"sub":"user-1","email":"user@example.com","preferred_username":"user1"}`), nil
+		default:
+			t.Fatalf("unexpected request to %s", req.URL)
+			return nil, nil
+		}
+	})
+
+	c := NewOAuth2Connector(cfg)
+	req := httptest.NewRequest(http.MethodGet, "/auth/my-idp/callback?code=abc&state=xyz", nil)
+
+	identity, err := c.HandleCallback(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if identity.Subject != "user-1" || identity.Email != "user@example.com" || identity.Username != "user1" {
+		t.Errorf("unexpected identity: %+v", identity)
+	}
+	if !strings.Contains(c.LoginURL("xyz"), cfg.AuthURL) {
+		t.Errorf("expected LoginURL to be built from the configured authURL")
+	}
+}
+
+func Test_Registry(t *testing.T) {
+	r := NewRegistry(NewGitHubConnector(GitHubConfig{}), NewGitLabConnector(GitLabConfig{}))
+
+	if _, ok := r.Get("github"); !ok {
+		t.Error("expected github connector to be registered")
+	}
+	if _, ok := r.Get("gitlab"); !ok {
+		t.Error("expected gitlab connector to be registered")
+	}
+	if _, ok := r.Get("bitbucket"); ok {
+		t.Error("expected unregistered connector to be absent")
+	}
+}
+
+func Test_NewRegistryFromConfig(t *testing.T) {
+	cfg := Config{
+		GitHub: &GitHubConfig{ClientID: "gh-id"},
+		GitLab: &GitLabConfig{ClientID: "gl-id"},
+		OAuth2: []OAuth2Config{{ConnectorID: "my-idp", ClientID: "id"}},
+	}
+
+	r, err := NewRegistryFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, id := range []string{"github", "gitlab", "my-idp"} {
+		if _, ok := r.Get(id); !ok {
+			t.Errorf("expected connector %q to be registered", id)
+		}
+	}
+}
+
+func Test_NewRegistryFromConfig_missingOAuth2ID(t *testing.T) {
+	cfg := Config{OAuth2: []OAuth2Config{{ClientID: "id"}}}
+
+	if _, err := NewRegistryFromConfig(cfg); err == nil {
+		t.Error("expected an error for an oauth2 entry missing an id")
+	}
+}
@@ -0,0 +1,65 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package keys
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+)
+
+// JWKS is the standard JWK Set document shape, meant to be served at
+// /.well-known/jwks.json so third-party services can verify
+// Console-issued JWTs.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK is a single RSA public key in standard JWK form.
+type JWK struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS returns every public key the ring currently knows about, active
+// or retired-but-not-evicted, in standard JWK form.
+func (kr *KeyRing) JWKS() JWKS {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0, len(kr.keys))}
+	for _, k := range kr.keys {
+		set.Keys = append(set.Keys, jwkFromPublicKey(k.kid, &k.private.PublicKey))
+	}
+	return set
+}
+
+// jwkFromPublicKey encodes pub as a standard RS256 signing JWK.
+func jwkFromPublicKey(kid string, pub *rsa.PublicKey) JWK {
+	return JWK{
+		Kid: kid,
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
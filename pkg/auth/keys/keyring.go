@@ -0,0 +1,226 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package keys maintains a rotating ring of RSA keys used to sign and
+// verify Console-issued JWTs. Rather than a single long-lived signing
+// key, the primary key is rotated on a configurable interval; retired
+// keys are kept around only for as long as a token they signed could
+// still be valid, then evicted.
+package keys
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultRingSize bounds how many keys (one primary plus retired ones)
+// the ring keeps at once, as a safety net in case maxTokenTTL outlives
+// several rotation intervals.
+const DefaultRingSize = 3
+
+// DefaultRotationInterval is how often RotateNow is called automatically
+// when Start is used.
+const DefaultRotationInterval = 24 * time.Hour
+
+// rsaKeySize is the modulus size used for every generated signing key.
+const rsaKeySize = 2048
+
+// now is overridden in tests so rotation and eviction can be exercised
+// without sleeping in real time.
+var now = time.Now
+
+// signingKey is one RSA key in the ring, together with the bookkeeping
+// needed to decide when it should be evicted.
+type signingKey struct {
+	kid       string
+	private   *rsa.PrivateKey
+	retiredAt time.Time // zero while this is the primary key
+}
+
+// KeyRing maintains the current primary signing key plus however many
+// retired keys are still within maxTokenTTL of their retirement, so
+// that tokens already handed out keep validating until they expire.
+type KeyRing struct {
+	mu               sync.RWMutex
+	keys             []*signingKey // keys[0] is always the current primary
+	ringSize         int
+	rotationInterval time.Duration
+	maxTokenTTL      time.Duration
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// Option configures optional KeyRing behaviour away from its defaults.
+type Option func(*KeyRing)
+
+// WithRingSize overrides DefaultRingSize.
+func WithRingSize(n int) Option {
+	return func(kr *KeyRing) { kr.ringSize = n }
+}
+
+// WithRotationInterval overrides DefaultRotationInterval.
+func WithRotationInterval(d time.Duration) Option {
+	return func(kr *KeyRing) { kr.rotationInterval = d }
+}
+
+// NewKeyRing creates a KeyRing with one freshly generated primary key.
+// maxTokenTTL must be at least as long as the longest-lived token this
+// ring will ever be asked to sign, so that a retired key is never
+// evicted while a token it signed could still be valid.
+//
+// rotationInterval and ringSize must together cover at least
+// maxTokenTTL (rotationInterval*(ringSize-1) >= maxTokenTTL): a key is
+// only ever evicted once it has been retired for maxTokenTTL, so a
+// ringSize too small for how often rotation happens would otherwise
+// have to choose between breaking that guarantee or growing the ring
+// without bound. NewKeyRing rejects configurations that don't leave
+// enough room instead of silently picking one of those.
+func NewKeyRing(maxTokenTTL time.Duration, opts ...Option) (*KeyRing, error) {
+	kr := &KeyRing{
+		ringSize:         DefaultRingSize,
+		rotationInterval: DefaultRotationInterval,
+		maxTokenTTL:      maxTokenTTL,
+	}
+	for _, opt := range opts {
+		opt(kr)
+	}
+	if kr.ringSize < 1 {
+		return nil, fmt.Errorf("keys: ringSize must be at least 1, got %d", kr.ringSize)
+	}
+	if retained := kr.rotationInterval * time.Duration(kr.ringSize-1); retained < kr.maxTokenTTL {
+		return nil, fmt.Errorf("keys: rotationInterval (%s) * (ringSize-1) (%d) = %s is shorter than maxTokenTTL (%s); a retired key would have to be evicted before its tokens expire", kr.rotationInterval, kr.ringSize-1, retained, kr.maxTokenTTL)
+	}
+
+	key, err := newSigningKey()
+	if err != nil {
+		return nil, fmt.Errorf("keys: unable to generate initial signing key: %w", err)
+	}
+	kr.keys = []*signingKey{key}
+	return kr, nil
+}
+
+// Start launches a background goroutine that calls RotateNow every
+// rotationInterval, until Stop is called.
+func (kr *KeyRing) Start() {
+	kr.stop = make(chan struct{})
+	go func() {
+		t := time.NewTicker(kr.rotationInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				kr.RotateNow()
+			case <-kr.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the background rotation goroutine started by Start, if any.
+func (kr *KeyRing) Stop() {
+	kr.stopOnce.Do(func() {
+		if kr.stop != nil {
+			close(kr.stop)
+		}
+	})
+}
+
+// RotateNow generates a fresh primary signing key immediately, retires
+// the previous primary, and evicts any key whose retirement is older
+// than maxTokenTTL. It is exposed as the Console's admin "rotate keys
+// now" API.
+func (kr *KeyRing) RotateNow() error {
+	fresh, err := newSigningKey()
+	if err != nil {
+		return fmt.Errorf("keys: unable to generate signing key: %w", err)
+	}
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	if len(kr.keys) > 0 {
+		kr.keys[0].retiredAt = now()
+	}
+	kr.keys = append([]*signingKey{fresh}, kr.keys...)
+	kr.evictLocked()
+	return nil
+}
+
+// evictLocked drops every retired key older than maxTokenTTL. A token
+// signed under a retired key is guaranteed to keep verifying until it
+// expires, so maxTokenTTL is the only thing allowed to evict a key;
+// NewKeyRing's validation of rotationInterval against ringSize is what
+// keeps the ring from growing without bound in the meantime. Callers
+// must hold kr.mu.
+func (kr *KeyRing) evictLocked() {
+	live := kr.keys[:0]
+	for _, k := range kr.keys {
+		if k.retiredAt.IsZero() || now().Sub(k.retiredAt) < kr.maxTokenTTL {
+			live = append(live, k)
+		}
+	}
+	kr.keys = live
+}
+
+// Primary returns the kid and private key currently used to sign new
+// tokens.
+func (kr *KeyRing) Primary() (kid string, key *rsa.PrivateKey) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	k := kr.keys[0]
+	return k.kid, k.private
+}
+
+// PublicKey returns the public key registered under kid, and whether
+// it was found among the active or still-retained retired keys.
+func (kr *KeyRing) PublicKey(kid string) (*rsa.PublicKey, bool) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	for _, k := range kr.keys {
+		if k.kid == kid {
+			return &k.private.PublicKey, true
+		}
+	}
+	return nil, false
+}
+
+// newSigningKey generates a fresh RSA key pair with a random kid.
+func newSigningKey() (*signingKey, error) {
+	priv, err := rsa.GenerateKey(rand.Reader, rsaKeySize)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := randomKid()
+	if err != nil {
+		return nil, err
+	}
+	return &signingKey{kid: kid, private: priv}, nil
+}
+
+// randomKid returns a fresh, URL-safe random key ID.
+func randomKid() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
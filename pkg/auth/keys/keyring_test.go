@@ -0,0 +1,193 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package keys
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// withClock temporarily overrides now so rotation/eviction can be
+// tested without sleeping in real time.
+func withClock(t *testing.T, at time.Time) {
+	t.Helper()
+	original := now
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = original })
+}
+
+func Test_KeyRing_SignVerify(t *testing.T) {
+	kr, err := NewKeyRing(time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tokenString, err := kr.Sign(jwt.StandardClaims{Subject: "alice"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var claims jwt.StandardClaims
+	if err := kr.Verify(tokenString, &claims); err != nil {
+		t.Fatalf("expected token to verify, got error: %v", err)
+	}
+	if claims.Subject != "alice" {
+		t.Errorf("unexpected subject: %q", claims.Subject)
+	}
+}
+
+func Test_KeyRing_Sign_setsKidHeader(t *testing.T) {
+	kr, err := NewKeyRing(time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantKid, _ := kr.Primary()
+
+	tokenString, err := kr.Sign(jwt.StandardClaims{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tok, _, err := new(jwt.Parser).ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("unable to parse token: %v", err)
+	}
+	if kid, _ := tok.Header["kid"].(string); kid != wantKid {
+		t.Errorf("expected kid header %q, got %q", wantKid, kid)
+	}
+}
+
+func Test_KeyRing_RotateNow_retiredKeyStillVerifies(t *testing.T) {
+	kr, err := NewKeyRing(time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tokenString, err := kr.Sign(jwt.StandardClaims{Subject: "alice"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := kr.RotateNow(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var claims jwt.StandardClaims
+	if err := kr.Verify(tokenString, &claims); err != nil {
+		t.Errorf("expected a token signed by the retired key to still verify, got: %v", err)
+	}
+}
+
+func Test_KeyRing_RotateNow_evictsExpiredKey(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	withClock(t, start)
+
+	kr, err := NewKeyRing(time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tokenString, err := kr.Sign(jwt.StandardClaims{Subject: "alice"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Retire the signing key...
+	if err := kr.RotateNow(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// ...and move the clock past maxTokenTTL so the next rotation
+	// evicts it.
+	withClock(t, start.Add(2*time.Minute))
+	if err := kr.RotateNow(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var claims jwt.StandardClaims
+	if err := kr.Verify(tokenString, &claims); err == nil {
+		t.Error("expected a token signed by an evicted key to fail verification")
+	}
+}
+
+func Test_KeyRing_ringSizeNeverEvictsALiveKey(t *testing.T) {
+	// DefaultRingSize is 3, so before the fix this rotated past the
+	// backstop and evicted aliceToken's key purely by rotation count,
+	// even though maxTokenTTL (1h) never actually elapsed.
+	kr, err := NewKeyRing(time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	aliceToken, err := kr.Sign(jwt.StandardClaims{Subject: "alice"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := kr.RotateNow(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	var claims jwt.StandardClaims
+	if err := kr.Verify(aliceToken, &claims); err != nil {
+		t.Errorf("expected a token signed under a key retired more than ringSize rotations ago, but still within maxTokenTTL, to verify: %v", err)
+	}
+	if got := len(kr.JWKS().Keys); got != 6 {
+		t.Errorf("expected all 6 keys (1 original + 5 rotations) to be retained since none have exceeded maxTokenTTL, got %d", got)
+	}
+}
+
+func Test_NewKeyRing_rejectsRingSizeTooSmallForMaxTokenTTL(t *testing.T) {
+	// rotationInterval*(ringSize-1) = 10min is shorter than maxTokenTTL
+	// (1h), so a retired key could be forced out of the ring before a
+	// token it signed expires. NewKeyRing must reject this up front
+	// rather than let evictLocked silently break that guarantee later.
+	_, err := NewKeyRing(time.Hour, WithRingSize(2), WithRotationInterval(10*time.Minute))
+	if err == nil {
+		t.Fatal("expected an error when rotationInterval*(ringSize-1) is shorter than maxTokenTTL")
+	}
+}
+
+func Test_KeyRing_JWKS(t *testing.T) {
+	kr, err := NewKeyRing(time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kid, _ := kr.Primary()
+
+	set := kr.JWKS()
+	if len(set.Keys) != 1 {
+		t.Fatalf("expected exactly 1 key, got %d", len(set.Keys))
+	}
+	got := set.Keys[0]
+	if got.Kid != kid || got.Kty != "RSA" || got.Alg != "RS256" || got.N == "" || got.E == "" {
+		t.Errorf("unexpected JWK: %+v", got)
+	}
+}
+
+func Test_KeyRing_PublicKey_unknownKid(t *testing.T) {
+	kr, err := NewKeyRing(time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := kr.PublicKey("does-not-exist"); ok {
+		t.Error("expected an unknown kid to be absent")
+	}
+}
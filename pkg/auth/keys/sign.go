@@ -0,0 +1,56 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package keys
+
+import (
+	"fmt"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Sign signs claims with the ring's current primary key, using RS256
+// and setting the standard "kid" header so Verify (or any third party
+// reading /.well-known/jwks.json) can select the right public key.
+func (kr *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	kid, key := kr.Primary()
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = kid
+	signed, err := tok.SignedString(key)
+	if err != nil {
+		return "", fmt.Errorf("keys: unable to sign token: %w", err)
+	}
+	return signed, nil
+}
+
+// Verify parses tokenString, selecting the verification key by the
+// "kid" set in its header, and returns its claims. It succeeds for
+// tokens signed by the current primary key or any retired key the ring
+// has not yet evicted, and fails once the signing key has been evicted.
+func (kr *KeyRing) Verify(tokenString string, claims jwt.Claims) error {
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		pub, ok := kr.PublicKey(kid)
+		if !ok {
+			return nil, fmt.Errorf("no signing key found for kid %q", kid)
+		}
+		return pub, nil
+	})
+	return err
+}
@@ -0,0 +1,207 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package oauth2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// httpClient is the client used to reach the issuer's discovery and
+// token endpoints. Tests replace it with one whose Transport is a
+// RoundTripFunc so no real network calls are made.
+var httpClient = http.DefaultClient
+
+// discoveryPath is appended to the issuer URL to locate its OpenID
+// Connect discovery document.
+const discoveryPath = "/.well-known/openid-configuration"
+
+// refreshSkew is subtracted from a token's expires_in so that Token
+// refreshes it slightly before the upstream would consider it expired.
+const refreshSkew = 60 * time.Second
+
+// cacheKey identifies a cached access token by the three-legged tuple
+// that scopes it: the issuer, the client asking, and who it's for.
+type cacheKey struct {
+	issuer   string
+	clientID string
+	audience string
+}
+
+// cachedToken is an access token together with the time it should be
+// considered stale and re-fetched, and the upstream's own expiry for
+// the token (expiresAt minus refreshSkew), which callers that hand the
+// token to a third party need in order to report its real lifetime.
+type cachedToken struct {
+	accessToken string
+	expiresAt   time.Time
+	realExpiry  time.Time
+}
+
+// TokenSource mints and caches access tokens for a single OAuth2
+// client-credentials keyfile, discovering the issuer's token endpoint
+// on first use and automatically refreshing the cached token shortly
+// before it expires.
+type TokenSource struct {
+	keyfile *Keyfile
+	scope   string
+
+	mu    sync.Mutex
+	cache map[cacheKey]cachedToken
+}
+
+// NewTokenSource returns a TokenSource that authenticates with keyfile,
+// requesting scope (which may be empty) on every token request.
+func NewTokenSource(keyfile *Keyfile, scope string) *TokenSource {
+	return &TokenSource{
+		keyfile: keyfile,
+		scope:   scope,
+		cache:   make(map[cacheKey]cachedToken),
+	}
+}
+
+// Token returns a valid access token and its real expiry (the
+// upstream's own expires_in, not the earlier time Token internally
+// refreshes it at), reusing the cached one if it isn't within
+// refreshSkew of expiring, and fetching a fresh one otherwise.
+func (ts *TokenSource) Token() (string, time.Time, error) {
+	key := ts.cacheKey()
+
+	ts.mu.Lock()
+	cached, ok := ts.cache[key]
+	ts.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.accessToken, cached.realExpiry, nil
+	}
+
+	return ts.refresh(key)
+}
+
+// InvalidateToken discards the cached access token, forcing the next
+// call to Token to fetch a fresh one. Callers should invoke this after
+// the upstream rejects a token with 401 Unauthorized, in case it was
+// revoked before its advertised expiry.
+func (ts *TokenSource) InvalidateToken() {
+	key := ts.cacheKey()
+	ts.mu.Lock()
+	delete(ts.cache, key)
+	ts.mu.Unlock()
+}
+
+func (ts *TokenSource) cacheKey() cacheKey {
+	return cacheKey{issuer: ts.keyfile.IssuerURL, clientID: ts.keyfile.ClientID, audience: ts.keyfile.Audience}
+}
+
+// refresh unconditionally fetches a fresh token and stores it under key.
+func (ts *TokenSource) refresh(key cacheKey) (string, time.Time, error) {
+	tokenEndpoint, err := discoverTokenEndpoint(ts.keyfile.IssuerURL)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("oauth2: discovery failed: %w", err)
+	}
+
+	fresh, err := fetchClientCredentialsToken(tokenEndpoint, ts.keyfile, ts.scope)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("oauth2: token request failed: %w", err)
+	}
+
+	ts.mu.Lock()
+	ts.cache[key] = *fresh
+	ts.mu.Unlock()
+
+	return fresh.accessToken, fresh.realExpiry, nil
+}
+
+// discoveryDocument is the subset of the OIDC discovery document
+// needed to locate the issuer's token endpoint.
+type discoveryDocument struct {
+	TokenEndpoint string `json:"token_endpoint"`
+}
+
+// discoverTokenEndpoint fetches and parses the issuer's OIDC discovery
+// document to find its token endpoint.
+func discoverTokenEndpoint(issuerURL string) (string, error) {
+	resp, err := httpClient.Get(strings.TrimSuffix(issuerURL, "/") + discoveryPath)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d from discovery endpoint", resp.StatusCode)
+	}
+	var doc discoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return "", err
+	}
+	if doc.TokenEndpoint == "" {
+		return "", fmt.Errorf("discovery document did not include a token_endpoint")
+	}
+	return doc.TokenEndpoint, nil
+}
+
+// fetchClientCredentialsToken performs the OAuth2 "client_credentials"
+// grant against tokenEndpoint and returns the resulting cachedToken.
+func fetchClientCredentialsToken(tokenEndpoint string, kf *Keyfile, scope string) (*cachedToken, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", kf.ClientID)
+	form.Set("client_secret", kf.ClientSecret)
+	if kf.Audience != "" {
+		form.Set("audience", kf.Audience)
+	}
+	if scope != "" {
+		form.Set("scope", scope)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d from token endpoint", resp.StatusCode)
+	}
+
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return nil, err
+	}
+	if tok.AccessToken == "" {
+		return nil, fmt.Errorf("token endpoint response did not include an access_token")
+	}
+
+	now := time.Now()
+	return &cachedToken{
+		accessToken: tok.AccessToken,
+		expiresAt:   now.Add(time.Duration(tok.ExpiresIn)*time.Second - refreshSkew),
+		realExpiry:  now.Add(time.Duration(tok.ExpiresIn) * time.Second),
+	}, nil
+}
@@ -0,0 +1,198 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package oauth2
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// RoundTripFunc lets a test stand in for the issuer's discovery and
+// token endpoints, without making any real network calls.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip .
+func (f RoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func withTestClient(t *testing.T, fn RoundTripFunc) {
+	t.Helper()
+	original := httpClient
+	httpClient = &http.Client{Transport: fn}
+	t.Cleanup(func() { httpClient = original })
+}
+
+func jsonResponse(body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+		Header:     make(http.Header),
+	}
+}
+
+func testKeyfile() *Keyfile {
+	return &Keyfile{
+		Type:         "client_credentials",
+		ClientID:     "client-1",
+		ClientSecret: "secret-1",
+		IssuerURL:    "https://idp.example.com",
+		Audience:     "https://k8s.example.com",
+	}
+}
+
+func Test_TokenSource_Token(t *testing.T) {
+	requests := 0
+	withTestClient(t, func(req *http.Request) (*http.Response, error) {
+		switch req.URL.String() {
+		case "https://idp.example.com/.well-known/openid-configuration":
+			return jsonResponse(`{"token_endpoint":"https://idp.example.com/oauth2/token"}`), nil
+		case "https://idp.example.com/oauth2/token":
+			requests++
+			return jsonResponse(fmt.Sprintf(`{"access_token":"tok-%d","expires_in":3600}`, requests)), nil
+		default:
+			t.Fatalf("unexpected request to %s", req.URL)
+			return nil, nil
+		}
+	})
+
+	ts := NewTokenSource(testKeyfile(), "")
+
+	before := time.Now()
+	tok, expiry, err := ts.Token()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tok != "tok-1" {
+		t.Errorf("expected tok-1, got %q", tok)
+	}
+	// expires_in was 3600s; the returned expiry is the upstream's real
+	// expiry, not refreshSkew-adjusted, so it should land close to +1h.
+	if expiry.Before(before.Add(55*time.Minute)) || expiry.After(before.Add(65*time.Minute)) {
+		t.Errorf("expected expiry around 1h from now, got %v", expiry)
+	}
+
+	// A second call within the token's lifetime should reuse the cache
+	// rather than hitting the token endpoint again.
+	tok2, expiry2, err := ts.Token()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tok2 != "tok-1" {
+		t.Errorf("expected cached tok-1, got %q", tok2)
+	}
+	if !expiry2.Equal(expiry) {
+		t.Errorf("expected the cached call to return the same expiry, got %v vs %v", expiry2, expiry)
+	}
+	if requests != 1 {
+		t.Errorf("expected exactly 1 token request, got %d", requests)
+	}
+}
+
+func Test_TokenSource_Token_refreshesNearExpiry(t *testing.T) {
+	requests := 0
+	withTestClient(t, func(req *http.Request) (*http.Response, error) {
+		switch req.URL.String() {
+		case "https://idp.example.com/.well-known/openid-configuration":
+			return jsonResponse(`{"token_endpoint":"https://idp.example.com/oauth2/token"}`), nil
+		case "https://idp.example.com/oauth2/token":
+			requests++
+			// expires_in is within refreshSkew, so the cached token
+			// should be considered stale immediately.
+			return jsonResponse(fmt.Sprintf(`{"access_token":"tok-%d","expires_in":30}`, requests)), nil
+		default:
+			t.Fatalf("unexpected request to %s", req.URL)
+			return nil, nil
+		}
+	})
+
+	ts := NewTokenSource(testKeyfile(), "")
+
+	if _, _, err := ts.Token(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := ts.Token(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected the near-expiry token to be refreshed, got %d requests", requests)
+	}
+}
+
+func Test_TokenSource_InvalidateToken_forcesRefresh(t *testing.T) {
+	requests := 0
+	withTestClient(t, func(req *http.Request) (*http.Response, error) {
+		switch req.URL.String() {
+		case "https://idp.example.com/.well-known/openid-configuration":
+			return jsonResponse(`{"token_endpoint":"https://idp.example.com/oauth2/token"}`), nil
+		case "https://idp.example.com/oauth2/token":
+			requests++
+			return jsonResponse(fmt.Sprintf(`{"access_token":"tok-%d","expires_in":3600}`, requests)), nil
+		default:
+			t.Fatalf("unexpected request to %s", req.URL)
+			return nil, nil
+		}
+	})
+
+	ts := NewTokenSource(testKeyfile(), "")
+
+	first, _, err := ts.Token()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Simulate the upstream rejecting first with a 401.
+	ts.InvalidateToken()
+
+	second, _, err := ts.Token()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first == second {
+		t.Error("expected InvalidateToken to force a fresh token on the next call")
+	}
+	if requests != 2 {
+		t.Errorf("expected exactly 2 token requests, got %d", requests)
+	}
+}
+
+func Test_TokenSource_Token_tokenEndpointError(t *testing.T) {
+	withTestClient(t, func(req *http.Request) (*http.Response, error) {
+		switch req.URL.String() {
+		case "https://idp.example.com/.well-known/openid-configuration":
+			return jsonResponse(`{"token_endpoint":"https://idp.example.com/oauth2/token"}`), nil
+		case "https://idp.example.com/oauth2/token":
+			return &http.Response{
+				StatusCode: http.StatusInternalServerError,
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`server error`)),
+				Header:     make(http.Header),
+			}, nil
+		default:
+			t.Fatalf("unexpected request to %s", req.URL)
+			return nil, nil
+		}
+	})
+
+	ts := NewTokenSource(testKeyfile(), "")
+	if _, _, err := ts.Token(); err == nil {
+		t.Error("expected an error when the token endpoint fails")
+	}
+}
@@ -0,0 +1,137 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package oidc
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// jwks is the standard JWK Set document served by an OIDC provider's
+// jwks_uri, as referenced by DiscoveryDocument.JWKSURI.
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// jwk is a single RSA public key in JWK form, restricted to the fields
+// needed to reconstruct a *rsa.PublicKey.
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// ValidateIDToken verifies the signature of idToken against the
+// issuer's published JWKS and checks the iss, aud and exp claims
+// before returning the token's claim set.
+func ValidateIDToken(idToken string, doc *DiscoveryDocument, clientID string) (jwt.MapClaims, error) {
+	set, err := fetchJWKS(doc.JWKSURI)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch jwks: %w", err)
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(idToken, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		key, err := set.key(kid)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if iss, _ := claims["iss"].(string); iss != doc.Issuer {
+		return nil, fmt.Errorf("id_token iss %q does not match issuer %q", iss, doc.Issuer)
+	}
+	if !audienceContains(claims["aud"], clientID) {
+		return nil, fmt.Errorf("id_token aud does not contain client_id %q", clientID)
+	}
+	if err := claims.Valid(); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// audienceContains reports whether the JWT "aud" claim, which per
+// RFC 7519 may be a single string or an array of strings, contains
+// clientID.
+func audienceContains(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fetchJWKS retrieves and parses the JWK Set document at uri.
+func fetchJWKS(uri string) (*jwks, error) {
+	resp, err := httpClient.Get(uri)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d from jwks_uri", resp.StatusCode)
+	}
+	var set jwks
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// key looks up the RSA public key with the given kid and reconstructs
+// it from its base64url-encoded modulus and exponent.
+func (s *jwks) key(kid string) (*rsa.PublicKey, error) {
+	for _, k := range s.Keys {
+		if k.Kid != kid {
+			continue
+		}
+		nBytes, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		eBytes, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(nBytes),
+			E: int(new(big.Int).SetBytes(eBytes).Int64()),
+		}, nil
+	}
+	return nil, fmt.Errorf("no jwks key found for kid %q", kid)
+}
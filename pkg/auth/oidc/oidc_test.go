@@ -0,0 +1,344 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package oidc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// RoundTripFunc lets a test stand in for an issuer's discovery, token
+// and jwks_uri endpoints, without making any real network calls.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip .
+func (f RoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// withTestClient temporarily swaps the package-level httpClient for one
+// backed by fn, restoring the original client once the test is done.
+func withTestClient(t *testing.T, fn RoundTripFunc) {
+	t.Helper()
+	original := httpClient
+	httpClient = &http.Client{Transport: fn}
+	t.Cleanup(func() { httpClient = original })
+}
+
+func jsonResponse(body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+		Header:     make(http.Header),
+	}
+}
+
+const testIssuer = "https://idp.example.com"
+
+func testDiscoveryDocument() *DiscoveryDocument {
+	return &DiscoveryDocument{
+		Issuer:                testIssuer,
+		AuthorizationEndpoint: testIssuer + "/authorize",
+		TokenEndpoint:         testIssuer + "/token",
+		JWKSURI:               testIssuer + "/jwks",
+	}
+}
+
+func Test_Discover(t *testing.T) {
+	withTestClient(t, func(req *http.Request) (*http.Response, error) {
+		if req.URL.String() != testIssuer+discoveryPath {
+			t.Fatalf("unexpected request to %s", req.URL)
+		}
+		return jsonResponse(`{
+			"issuer": "https://idp.example.com",
+			"authorization_endpoint": "https://idp.example.com/authorize",
+			"token_endpoint": "https://idp.example.com/token",
+			"jwks_uri": "https://idp.example.com/jwks"
+		}`), nil
+	})
+
+	doc, err := Discover(testIssuer)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if doc.Issuer != testIssuer || doc.AuthorizationEndpoint != testIssuer+"/authorize" {
+		t.Errorf("unexpected discovery document: %+v", doc)
+	}
+}
+
+func Test_BuildAuthURL(t *testing.T) {
+	doc := testDiscoveryDocument()
+	authURL, err := BuildAuthURL(doc, AuthParams{
+		ClientID:      "client-123",
+		RedirectURL:   "https://console.example.com/auth/oidc/callback",
+		Scopes:        []string{"openid", "email"},
+		State:         "state-abc",
+		CodeChallenge: "challenge-xyz",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("unable to parse built auth URL: %v", err)
+	}
+	q := u.Query()
+	if q.Get("client_id") != "client-123" ||
+		q.Get("redirect_uri") != "https://console.example.com/auth/oidc/callback" ||
+		q.Get("scope") != "openid email" ||
+		q.Get("state") != "state-abc" ||
+		q.Get("code_challenge") != "challenge-xyz" ||
+		q.Get("code_challenge_method") != "S256" ||
+		q.Get("response_type") != "code" {
+		t.Errorf("unexpected auth URL query: %s", u.RawQuery)
+	}
+}
+
+func Test_GeneratePKCE(t *testing.T) {
+	state, verifier, challenge, err := GeneratePKCE()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state == "" || verifier == "" || challenge == "" {
+		t.Fatalf("expected non-empty state, verifier and challenge")
+	}
+	if challenge != codeChallengeS256(verifier) {
+		t.Errorf("challenge does not match the verifier it was derived from")
+	}
+
+	state2, verifier2, _, err := GeneratePKCE()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state == state2 || verifier == verifier2 {
+		t.Errorf("expected GeneratePKCE to return fresh values on each call")
+	}
+}
+
+func Test_ExchangeCode(t *testing.T) {
+	doc := testDiscoveryDocument()
+	withTestClient(t, func(req *http.Request) (*http.Response, error) {
+		if req.URL.String() != doc.TokenEndpoint {
+			t.Fatalf("unexpected request to %s", req.URL)
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			t.Fatalf("unable to parse request body: %v", err)
+		}
+		if form.Get("grant_type") != "authorization_code" ||
+			form.Get("client_id") != "client-123" ||
+			form.Get("code") != "auth-code" ||
+			form.Get("code_verifier") != "verifier-xyz" ||
+			form.Get("redirect_uri") != "https://console.example.com/auth/oidc/callback" {
+			t.Fatalf("unexpected token request form: %v", form)
+		}
+		return jsonResponse(`{"access_token":"at-123","id_token":"id-123","token_type":"Bearer","expires_in":3600}`), nil
+	})
+
+	tok, err := ExchangeCode(doc, "client-123", "auth-code", "verifier-xyz", "https://console.example.com/auth/oidc/callback")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tok.IDToken != "id-123" || tok.AccessToken != "at-123" || tok.ExpiresIn != 3600 {
+		t.Errorf("unexpected token response: %+v", tok)
+	}
+}
+
+func Test_ExchangeCode_missingIDToken(t *testing.T) {
+	doc := testDiscoveryDocument()
+	withTestClient(t, func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(`{"access_token":"at-123","token_type":"Bearer","expires_in":3600}`), nil
+	})
+
+	if _, err := ExchangeCode(doc, "client-123", "auth-code", "verifier-xyz", "https://console.example.com/auth/oidc/callback"); err == nil {
+		t.Fatal("expected an error when the token response has no id_token")
+	}
+}
+
+// issuedIDToken signs an id_token with priv under kid and serves pub
+// at jwksPath for the duration of the test.
+func issuedIDToken(t *testing.T, priv *rsa.PrivateKey, kid, clientID string, expiresIn time.Duration) string {
+	t.Helper()
+	claims := jwt.MapClaims{
+		"iss": testIssuer,
+		"aud": clientID,
+		"sub": "user-42",
+		"exp": time.Now().Add(expiresIn).Unix(),
+		"iat": time.Now().Unix(),
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = kid
+	signed, err := tok.SignedString(priv)
+	if err != nil {
+		t.Fatalf("unable to sign test id_token: %v", err)
+	}
+	return signed
+}
+
+func jwksBody(kid string, pub *rsa.PublicKey) string {
+	n := base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+	e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	return `{"keys":[{"kid":"` + kid + `","kty":"RSA","n":"` + n + `","e":"` + e + `"}]}`
+}
+
+func Test_ValidateIDToken(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate test key: %v", err)
+	}
+	doc := testDiscoveryDocument()
+	idToken := issuedIDToken(t, priv, "kid-1", "client-123", time.Hour)
+
+	withTestClient(t, func(req *http.Request) (*http.Response, error) {
+		if req.URL.String() != doc.JWKSURI {
+			t.Fatalf("unexpected request to %s", req.URL)
+		}
+		return jsonResponse(jwksBody("kid-1", &priv.PublicKey)), nil
+	})
+
+	claims, err := ValidateIDToken(idToken, doc, "client-123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claims["sub"] != "user-42" {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+}
+
+func Test_ValidateIDToken_wrongAudience(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate test key: %v", err)
+	}
+	doc := testDiscoveryDocument()
+	idToken := issuedIDToken(t, priv, "kid-1", "some-other-client", time.Hour)
+
+	withTestClient(t, func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(jwksBody("kid-1", &priv.PublicKey)), nil
+	})
+
+	if _, err := ValidateIDToken(idToken, doc, "client-123"); err == nil {
+		t.Fatal("expected an error for a token issued to a different client_id")
+	}
+}
+
+func Test_ValidateIDToken_expired(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate test key: %v", err)
+	}
+	doc := testDiscoveryDocument()
+	idToken := issuedIDToken(t, priv, "kid-1", "client-123", -time.Hour)
+
+	withTestClient(t, func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(jwksBody("kid-1", &priv.PublicKey)), nil
+	})
+
+	if _, err := ValidateIDToken(idToken, doc, "client-123"); err == nil {
+		t.Fatal("expected an error for an expired id_token")
+	}
+}
+
+func Test_ValidateIDToken_unknownKid(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate test key: %v", err)
+	}
+	doc := testDiscoveryDocument()
+	idToken := issuedIDToken(t, priv, "kid-unknown", "client-123", time.Hour)
+
+	withTestClient(t, func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(jwksBody("kid-1", &priv.PublicKey)), nil
+	})
+
+	if _, err := ValidateIDToken(idToken, doc, "client-123"); err == nil {
+		t.Fatal("expected an error when the id_token's kid is not present in the jwks")
+	}
+}
+
+func Test_Login_endToEnd(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate test key: %v", err)
+	}
+
+	var gotRedirectURI string
+	withTestClient(t, func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, discoveryPath):
+			return jsonResponse(`{
+				"issuer": "https://idp.example.com",
+				"authorization_endpoint": "https://idp.example.com/authorize",
+				"token_endpoint": "https://idp.example.com/token",
+				"jwks_uri": "https://idp.example.com/jwks"
+			}`), nil
+		case req.URL.Path == "/token":
+			body, _ := ioutil.ReadAll(req.Body)
+			form, _ := url.ParseQuery(string(body))
+			gotRedirectURI = form.Get("redirect_uri")
+			idToken := issuedIDToken(t, priv, "kid-1", "client-123", time.Hour)
+			return jsonResponse(`{"access_token":"at","id_token":"` + idToken + `","token_type":"Bearer","expires_in":3600}`), nil
+		case req.URL.Path == "/jwks":
+			return jsonResponse(jwksBody("kid-1", &priv.PublicKey)), nil
+		default:
+			t.Fatalf("unexpected request to %s", req.URL)
+			return nil, nil
+		}
+	})
+
+	origOpenBrowser := openBrowser
+	openBrowser = func(authURL string) error {
+		u, err := url.Parse(authURL)
+		if err != nil {
+			t.Fatalf("unable to parse authorization URL: %v", err)
+		}
+		go func() {
+			resp, err := http.Get(u.Query().Get("redirect_uri") + "?state=" + u.Query().Get("state") + "&code=auth-code")
+			if err != nil {
+				t.Errorf("callback request failed: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+		return nil
+	}
+	t.Cleanup(func() { openBrowser = origOpenBrowser })
+
+	identity, err := Login(testIssuer, "client-123", []string{"openid"}, 18921)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if identity.Claims["sub"] != "user-42" {
+		t.Errorf("unexpected identity claims: %+v", identity.Claims)
+	}
+	if !strings.Contains(gotRedirectURI, "/oauth/callback") {
+		t.Errorf("unexpected redirect_uri sent to token endpoint: %s", gotRedirectURI)
+	}
+}
@@ -0,0 +1,79 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// adminScope is the JWT scope required to call admin-only endpoints
+// such as serverRotateSigningKey.
+const adminScope = "admin"
+
+// adminClaims is the subset of a Console JWT's claims serverRotateSigningKey
+// needs in order to check for the admin scope.
+type adminClaims struct {
+	Scope string `json:"scope"`
+	jwt.StandardClaims
+}
+
+// serverRotateSigningKey is the admin API backing a manual "rotate
+// signing keys now" action: it forces signingKeys to generate a fresh
+// primary key immediately, ahead of its regular rotation interval.
+// Access is guarded by requiring an admin-scoped Console JWT.
+func serverRotateSigningKey(w http.ResponseWriter, r *http.Request) {
+	if signingKeys == nil {
+		http.Error(w, "signing key rotation is not configured", http.StatusInternalServerError)
+		return
+	}
+
+	tokenString := bearerToken(r)
+	if tokenString == "" {
+		http.Error(w, "missing Console JWT", http.StatusUnauthorized)
+		return
+	}
+
+	var claims adminClaims
+	if err := signingKeys.Verify(tokenString, &claims); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if !hasAdminScope(claims.Scope) {
+		http.Error(w, "admin scope required", http.StatusForbidden)
+		return
+	}
+
+	if err := signingKeys.RotateNow(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// hasAdminScope reports whether the space-delimited scope claim
+// includes adminScope.
+func hasAdminScope(scope string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == adminScope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/minio/mcs/pkg/auth/connectors"
+	"github.com/minio/minio-go/v6/pkg/credentials"
+)
+
+const (
+	connectorsLoginPrefix    = "/auth/"
+	connectorsLoginSuffix    = "/login"
+	connectorsCallbackSuffix = "/callback"
+)
+
+// MountConnectorRoutes registers the "/auth/{connector_id}/login" and
+// "/auth/{connector_id}/callback" routes for every Connector held by
+// registry onto mux.
+func MountConnectorRoutes(mux *http.ServeMux, registry *connectors.Registry) {
+	mux.HandleFunc(connectorsLoginPrefix, func(w http.ResponseWriter, r *http.Request) {
+		id, kind, ok := parseConnectorPath(r.URL.Path)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		connector, found := registry.Get(id)
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		switch kind {
+		case connectorsLoginSuffix:
+			serverLoginConnector(connector, w, r)
+		case connectorsCallbackSuffix:
+			serverCallbackConnector(connector, w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// parseConnectorPath splits "/auth/{connector_id}/{login,callback}" into
+// its connector_id and suffix ("/login" or "/callback").
+func parseConnectorPath(path string) (id, suffix string, ok bool) {
+	rest := strings.TrimPrefix(path, connectorsLoginPrefix)
+	for _, s := range []string{connectorsLoginSuffix, connectorsCallbackSuffix} {
+		if strings.HasSuffix(rest, s) {
+			return strings.TrimSuffix(rest, s), s, true
+		}
+	}
+	return "", "", false
+}
+
+// serverLoginConnector redirects the browser to connector's provider to
+// begin the login flow, embedding a random anti-CSRF state value.
+func serverLoginConnector(connector connectors.Connector, w http.ResponseWriter, r *http.Request) {
+	state, err := randomState()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     connectorStateCookieName(connector.ID()),
+		Value:    state,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		Path:     connectorsLoginPrefix,
+	})
+	http.Redirect(w, r, connector.LoginURL(state), http.StatusFound)
+}
+
+// serverCallbackConnector completes connector's login flow and, on
+// success, mints the same session JWT that serverMkube validates so
+// all existing handlers work regardless of which connector was used.
+func serverCallbackConnector(connector connectors.Connector, w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(connectorStateCookieName(connector.ID()))
+	if err != nil || cookie.Value == "" || cookie.Value != r.URL.Query().Get("state") {
+		http.Error(w, "invalid or missing state", http.StatusBadRequest)
+		return
+	}
+
+	identity, err := connector.HandleCallback(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	creds := &credentials.Value{
+		AccessKeyID:  identity.Subject,
+		SessionToken: identity.Email,
+	}
+	sessionJWT, err := issueSessionJWT(creds, []string{identity.ConnectorID}, "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(oidcLoginResponse{Token: sessionJWT})
+}
+
+// connectorStateCookieName returns the anti-CSRF state cookie name used
+// for the given connector ID's login/callback round-trip.
+func connectorStateCookieName(connectorID string) string {
+	return "mcs-auth-state-" + connectorID
+}
+
+// randomState returns a fresh, hex-encoded random anti-CSRF state value.
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
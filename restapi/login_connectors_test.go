@@ -0,0 +1,151 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/minio/mcs/pkg/auth/connectors"
+)
+
+// fakeConnector is a minimal connectors.Connector stand-in so the
+// restapi routing/cookie-handling layer can be tested without any real
+// provider round trip.
+type fakeConnector struct {
+	id       string
+	identity connectors.Identity
+	err      error
+}
+
+func (c *fakeConnector) ID() string { return c.id }
+
+func (c *fakeConnector) LoginURL(state string) string {
+	return "https://idp.example.com/authorize?state=" + state
+}
+
+func (c *fakeConnector) HandleCallback(r *http.Request) (connectors.Identity, error) {
+	if c.err != nil {
+		return connectors.Identity{}, c.err
+	}
+	return c.identity, nil
+}
+
+func newTestConnectorMux(conns ...connectors.Connector) *http.ServeMux {
+	var mux http.ServeMux
+	MountConnectorRoutes(&mux, connectors.NewRegistry(conns...))
+	return &mux
+}
+
+func Test_MountConnectorRoutes_unknownConnector404(t *testing.T) {
+	mux := newTestConnectorMux(&fakeConnector{id: "github"})
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/bitbucket/login", nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unregistered connector, got %d", recorder.Code)
+	}
+}
+
+func Test_MountConnectorRoutes_login_setsStateCookieAndRedirects(t *testing.T) {
+	mux := newTestConnectorMux(&fakeConnector{id: "github"})
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/github/login", nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusFound {
+		t.Fatalf("expected a redirect to the provider, got %d", recorder.Code)
+	}
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != connectorStateCookieName("github") || cookies[0].Value == "" {
+		t.Fatalf("expected a non-empty state cookie to be set, got %+v", cookies)
+	}
+}
+
+func Test_MountConnectorRoutes_callback_missingStateCookie(t *testing.T) {
+	mux := newTestConnectorMux(&fakeConnector{id: "github"})
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/github/callback?state=xyz&code=abc", nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected a missing state cookie to be rejected, got %d", recorder.Code)
+	}
+}
+
+func Test_MountConnectorRoutes_callback_stateMismatch(t *testing.T) {
+	mux := newTestConnectorMux(&fakeConnector{id: "github"})
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/github/callback?state=xyz&code=abc", nil)
+	req.AddCookie(&http.Cookie{Name: connectorStateCookieName("github"), Value: "not-xyz"})
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected a mismatched state to be rejected, got %d", recorder.Code)
+	}
+}
+
+func Test_MountConnectorRoutes_callback_success(t *testing.T) {
+	identity := connectors.Identity{ConnectorID: "github", Subject: "42", Email: "octocat@example.com", Username: "octocat"}
+	mux := newTestConnectorMux(&fakeConnector{id: "github", identity: identity})
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/github/callback?state=xyz&code=abc", nil)
+	req.AddCookie(&http.Cookie{Name: connectorStateCookieName("github"), Value: "xyz"})
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected a successful callback to return 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var body oidcLoginResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+		t.Fatalf("unable to decode response body: %v", err)
+	}
+	if body.Token == "" {
+		t.Error("expected a non-empty session token")
+	}
+
+	creds, err := authenticateSessionJWT(body.Token)
+	if err != nil {
+		t.Fatalf("expected the minted token to authenticate, got: %v", err)
+	}
+	if creds.AccessKeyID != identity.Subject || creds.SessionToken != identity.Email {
+		t.Errorf("unexpected credentials in minted token: %+v", creds)
+	}
+}
+
+func Test_MountConnectorRoutes_callback_connectorError(t *testing.T) {
+	mux := newTestConnectorMux(&fakeConnector{id: "github", err: errors.New("token exchange failed")})
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/github/callback?state=xyz&code=abc", nil)
+	req.AddCookie(&http.Cookie{Name: connectorStateCookieName("github"), Value: "xyz"})
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("expected a connector error to surface as a 500, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,190 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/minio/mcs/pkg/auth/oidc"
+	"github.com/minio/minio-go/v6/pkg/credentials"
+)
+
+const (
+	oidcLoginPath    = "/api/v1/login/oidc"
+	oidcCallbackPath = "/api/v1/login/oidc/callback"
+
+	oidcStateCookieName    = "mcs-auth-oidc-state"
+	oidcVerifierCookieName = "mcs-auth-oidc-verifier"
+)
+
+// IDPConfig holds the settings needed to drive an OpenID Connect
+// Authorization Code + PKCE login against a third-party identity
+// provider from Console routes. It is meant to be embedded in the
+// Console server's configuration alongside the existing static
+// access-key/secret settings.
+type IDPConfig struct {
+	// IssuerURL is the OIDC issuer, e.g. "https://accounts.example.com".
+	IssuerURL string
+	// ClientID is the OAuth2 client_id registered with the issuer.
+	ClientID string
+	// Scopes requested during the Authorization Code flow. "openid" is
+	// always required and is added automatically if missing.
+	Scopes []string
+	// CallbackURL is this Console's own, externally reachable
+	// "/api/v1/login/oidc/callback" URL, registered as a redirect URI
+	// with the identity provider.
+	CallbackURL string
+}
+
+// oidcLoginResponse is returned by serverCallbackOIDC once the redirect
+// flow completes and the identity has been wrapped into a session JWT.
+type oidcLoginResponse struct {
+	Token string `json:"token"`
+}
+
+// MountOIDCLoginRoutes registers oidcLoginPath and oidcCallbackPath on
+// mux, driving an OIDC Authorization Code + PKCE login against cfg's
+// identity provider across the two separate requests a real, remote
+// browser makes: one to start the flow, one for the provider's redirect.
+func MountOIDCLoginRoutes(mux *http.ServeMux, cfg IDPConfig) {
+	mux.HandleFunc(oidcLoginPath, func(w http.ResponseWriter, r *http.Request) {
+		serverLoginOIDC(cfg, w, r)
+	})
+	mux.HandleFunc(oidcCallbackPath, func(w http.ResponseWriter, r *http.Request) {
+		serverCallbackOIDC(cfg, w, r)
+	})
+}
+
+// serverLoginOIDC discovers cfg's identity provider, stashes this
+// login attempt's anti-CSRF state and PKCE code_verifier in cookies,
+// and redirects the caller's browser to the provider's authorization
+// endpoint. The provider will in turn redirect back to
+// oidcCallbackPath, which serverCallbackOIDC handles.
+func serverLoginOIDC(cfg IDPConfig, w http.ResponseWriter, r *http.Request) {
+	doc, err := oidc.Discover(cfg.IssuerURL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	state, verifier, challenge, err := oidc.GeneratePKCE()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	authURL, err := oidc.BuildAuthURL(doc, oidc.AuthParams{
+		ClientID:      cfg.ClientID,
+		RedirectURL:   cfg.CallbackURL,
+		Scopes:        oidcScopes(cfg.Scopes),
+		State:         state,
+		CodeChallenge: challenge,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	secure := r.TLS != nil
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookieName, Value: state, HttpOnly: true, Secure: secure, Path: oidcCallbackPath})
+	http.SetCookie(w, &http.Cookie{Name: oidcVerifierCookieName, Value: verifier, HttpOnly: true, Secure: secure, Path: oidcCallbackPath})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// serverCallbackOIDC completes the Authorization Code + PKCE flow
+// started by serverLoginOIDC: it checks the returned state against the
+// cookie set earlier, exchanges the authorization code and PKCE
+// code_verifier for tokens, validates the id_token, and wraps the
+// resulting identity into the Console's existing session JWT so that
+// downstream handlers such as serverMkube continue to work unchanged.
+func serverCallbackOIDC(cfg IDPConfig, w http.ResponseWriter, r *http.Request) {
+	stateCookie, err := r.Cookie(oidcStateCookieName)
+	if err != nil || stateCookie.Value == "" || stateCookie.Value != r.URL.Query().Get("state") {
+		http.Error(w, "invalid or missing state", http.StatusBadRequest)
+		return
+	}
+	verifierCookie, err := r.Cookie(oidcVerifierCookieName)
+	if err != nil || verifierCookie.Value == "" {
+		http.Error(w, "missing code_verifier", http.StatusBadRequest)
+		return
+	}
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		http.Error(w, "callback request is missing the authorization code", http.StatusBadRequest)
+		return
+	}
+
+	doc, err := oidc.Discover(cfg.IssuerURL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tok, err := oidc.ExchangeCode(doc, cfg.ClientID, code, verifierCookie.Value, cfg.CallbackURL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	claims, err := oidc.ValidateIDToken(tok.IDToken, doc, cfg.ClientID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	creds := &credentials.Value{
+		AccessKeyID:  stringClaim(claims, "sub"),
+		SessionToken: tok.IDToken,
+	}
+	sessionJWT, err := issueSessionJWT(creds, oidcScopes(cfg.Scopes), "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(oidcLoginResponse{Token: sessionJWT})
+}
+
+// oidcScopes returns scopes with "openid" included, as required by
+// every OpenID Connect Authorization Code request.
+func oidcScopes(scopes []string) []string {
+	if containsString(scopes, "openid") {
+		return scopes
+	}
+	return append([]string{"openid"}, scopes...)
+}
+
+// containsString reports whether needle is present in haystack.
+func containsString(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
+
+// stringClaim returns the string value of the named claim, or the
+// empty string if it is absent or not a string.
+func stringClaim(claims map[string]interface{}, name string) string {
+	if v, ok := claims[name].(string); ok {
+		return v
+	}
+	return ""
+}
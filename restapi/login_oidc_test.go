@@ -0,0 +1,205 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// testOIDCIDP starts a local HTTP server standing in for an OIDC
+// issuer's discovery, token and jwks endpoints, signing id_tokens with
+// priv under kid "kid-1".
+func testOIDCIDP(t *testing.T, priv *rsa.PrivateKey) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	var mux http.ServeMux
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 srv.URL,
+			"authorization_endpoint": srv.URL + "/authorize",
+			"token_endpoint":         srv.URL + "/token",
+			"jwks_uri":               srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		claims := jwt.MapClaims{
+			"iss": srv.URL,
+			"aud": "client-123",
+			"sub": "user-42",
+			"exp": time.Now().Add(time.Hour).Unix(),
+			"iat": time.Now().Unix(),
+		}
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		tok.Header["kid"] = "kid-1"
+		idToken, err := tok.SignedString(priv)
+		if err != nil {
+			t.Fatalf("unable to sign test id_token: %v", err)
+		}
+		fmt.Fprintf(w, `{"access_token":"at-123","id_token":"%s","token_type":"Bearer","expires_in":3600}`, idToken)
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		n := base64.RawURLEncoding.EncodeToString(priv.PublicKey.N.Bytes())
+		e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(priv.PublicKey.E)).Bytes())
+		fmt.Fprintf(w, `{"keys":[{"kid":"kid-1","kty":"RSA","n":"%s","e":"%s"}]}`, n, e)
+	})
+	srv = httptest.NewServer(&mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func Test_serverLoginOIDC_setsStateAndVerifierCookiesAndRedirects(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate test key: %v", err)
+	}
+	idp := testOIDCIDP(t, priv)
+	cfg := IDPConfig{IssuerURL: idp.URL, ClientID: "client-123", CallbackURL: "https://console.example.com" + oidcCallbackPath}
+
+	req := httptest.NewRequest(http.MethodGet, oidcLoginPath, nil)
+	recorder := httptest.NewRecorder()
+
+	serverLoginOIDC(cfg, recorder, req)
+
+	resp := recorder.Result()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected a redirect to the provider, got %d", resp.StatusCode)
+	}
+	var stateCookie, verifierCookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		switch c.Name {
+		case oidcStateCookieName:
+			stateCookie = c
+		case oidcVerifierCookieName:
+			verifierCookie = c
+		}
+	}
+	if stateCookie == nil || stateCookie.Value == "" {
+		t.Fatal("expected a non-empty state cookie to be set")
+	}
+	if verifierCookie == nil || verifierCookie.Value == "" {
+		t.Fatal("expected a non-empty code_verifier cookie to be set")
+	}
+
+	authURL, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("unable to parse redirect location: %v", err)
+	}
+	if authURL.Query().Get("state") != stateCookie.Value {
+		t.Errorf("expected the redirect's state to match the state cookie")
+	}
+}
+
+func Test_serverCallbackOIDC_missingStateCookie(t *testing.T) {
+	cfg := IDPConfig{IssuerURL: "https://idp.example.com", ClientID: "client-123"}
+	req := httptest.NewRequest(http.MethodGet, oidcCallbackPath+"?state=xyz&code=abc", nil)
+	recorder := httptest.NewRecorder()
+
+	serverCallbackOIDC(cfg, recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected a missing state cookie to be rejected, got %d", recorder.Code)
+	}
+}
+
+func Test_serverCallbackOIDC_stateMismatch(t *testing.T) {
+	cfg := IDPConfig{IssuerURL: "https://idp.example.com", ClientID: "client-123"}
+	req := httptest.NewRequest(http.MethodGet, oidcCallbackPath+"?state=xyz&code=abc", nil)
+	req.AddCookie(&http.Cookie{Name: oidcStateCookieName, Value: "not-xyz"})
+	req.AddCookie(&http.Cookie{Name: oidcVerifierCookieName, Value: "verifier-1"})
+	recorder := httptest.NewRecorder()
+
+	serverCallbackOIDC(cfg, recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected a mismatched state to be rejected, got %d", recorder.Code)
+	}
+}
+
+func Test_serverCallbackOIDC_missingVerifierCookie(t *testing.T) {
+	cfg := IDPConfig{IssuerURL: "https://idp.example.com", ClientID: "client-123"}
+	req := httptest.NewRequest(http.MethodGet, oidcCallbackPath+"?state=xyz&code=abc", nil)
+	req.AddCookie(&http.Cookie{Name: oidcStateCookieName, Value: "xyz"})
+	recorder := httptest.NewRecorder()
+
+	serverCallbackOIDC(cfg, recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected a missing code_verifier cookie to be rejected, got %d", recorder.Code)
+	}
+}
+
+func Test_serverLoginOIDC_serverCallbackOIDC_roundTrip(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate test key: %v", err)
+	}
+	idp := testOIDCIDP(t, priv)
+	cfg := IDPConfig{IssuerURL: idp.URL, ClientID: "client-123", CallbackURL: "https://console.example.com" + oidcCallbackPath}
+
+	loginReq := httptest.NewRequest(http.MethodGet, oidcLoginPath, nil)
+	loginRecorder := httptest.NewRecorder()
+	serverLoginOIDC(cfg, loginRecorder, loginReq)
+
+	loginResp := loginRecorder.Result()
+	authURL, err := url.Parse(loginResp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("unable to parse redirect location: %v", err)
+	}
+	state := authURL.Query().Get("state")
+
+	callbackReq := httptest.NewRequest(http.MethodGet, oidcCallbackPath+"?state="+state+"&code=test-code", nil)
+	for _, c := range loginResp.Cookies() {
+		callbackReq.AddCookie(c)
+	}
+	callbackRecorder := httptest.NewRecorder()
+	serverCallbackOIDC(cfg, callbackRecorder, callbackReq)
+
+	callbackResp := callbackRecorder.Result()
+	if callbackResp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(callbackResp.Body)
+		t.Fatalf("expected a successful callback to return 200, got %d: %s", callbackResp.StatusCode, body)
+	}
+
+	var body oidcLoginResponse
+	if err := json.NewDecoder(callbackResp.Body).Decode(&body); err != nil {
+		t.Fatalf("unable to decode response body: %v", err)
+	}
+	if body.Token == "" {
+		t.Fatal("expected a non-empty session token")
+	}
+
+	creds, err := authenticateSessionJWT(body.Token)
+	if err != nil {
+		t.Fatalf("expected the minted token to authenticate, got: %v", err)
+	}
+	if creds.AccessKeyID != "user-42" {
+		t.Errorf("expected the session token's subject to be the id_token's sub claim, got %q", creds.AccessKeyID)
+	}
+}
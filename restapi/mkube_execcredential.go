@@ -0,0 +1,204 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// execCredentialAccept is the Accept header kubectl sends when it
+// invokes the Console as a client.authentication.k8s.io/v1beta1
+// ExecCredential provider.
+const execCredentialAccept = "application/json;as=ExecCredential;v=v1beta1;g=client.authentication.k8s.io"
+
+// execCredentialExpirySkew is subtracted from the underlying token's
+// expiry so that kubectl re-invokes the plugin slightly before the
+// token actually expires, rather than racing a request against one
+// that has just gone stale.
+const execCredentialExpirySkew = 10 * time.Second
+
+// execCredential is the response body shape kubectl's client-go exec
+// credential plugin machinery expects.
+type execCredential struct {
+	APIVersion string               `json:"apiVersion"`
+	Kind       string               `json:"kind"`
+	Status     execCredentialStatus `json:"status"`
+}
+
+// execCredentialStatus carries the short-lived token and its expiry.
+type execCredentialStatus struct {
+	Token               string `json:"token"`
+	ExpirationTimestamp string `json:"expirationTimestamp"`
+}
+
+// IsExecCredentialRequest reports whether r was made by kubectl's exec
+// credential plugin, based on its Accept header, as opposed to a
+// regular Console API client hitting the same mkube endpoint.
+func IsExecCredentialRequest(r *http.Request) bool {
+	return r.Header.Get("Accept") == execCredentialAccept
+}
+
+// serverMkubeExecCredential is the ExecCredential-flavored sibling of
+// serverMkube: it validates the caller's Console JWT, exchanges it for
+// the short-lived Kubernetes bearer token minted for the configured
+// mkube service account, and wraps that token in the JSON shape
+// kubectl expects instead of proxying the upstream response body as-is.
+func serverMkubeExecCredential(client *http.Client, w http.ResponseWriter, r *http.Request) {
+	tokenString := bearerToken(r)
+	if tokenString == "" {
+		http.Error(w, "no mkube service account token provided", http.StatusInternalServerError)
+		return
+	}
+
+	creds, err := authenticateSessionJWT(tokenString)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sessionExpiry, err := jwtExpiry(tokenString)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	bearer, bearerExpiry, resp, err := callMkubeUpstream(client, r, creds.SessionToken)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		http.Error(w, fmt.Sprintf("mkube upstream returned status %d", resp.StatusCode), http.StatusInternalServerError)
+		return
+	}
+
+	// The bearer actually handed to kubectl is the upstream token, not
+	// the caller's Console session JWT, so its own expiry - not the
+	// session's - is what determines when kubectl must re-invoke this
+	// plugin. Only the static-token path (no OAuth2 TokenSource
+	// configured) has no independent expiry of its own, in which case
+	// the bearer and the session JWT are the same token.
+	expiry := sessionExpiry
+	if !bearerExpiry.IsZero() {
+		expiry = bearerExpiry
+	}
+
+	cred := execCredential{
+		APIVersion: "client.authentication.k8s.io/v1beta1",
+		Kind:       "ExecCredential",
+		Status: execCredentialStatus{
+			Token:               bearer,
+			ExpirationTimestamp: expiry.Add(-execCredentialExpirySkew).UTC().Format(time.RFC3339),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cred)
+}
+
+// callMkubeUpstream proxies r to the mkube upstream using the bearer
+// token from mkubeUpstreamToken (staticToken unless --mkube-oauth2-keyfile
+// is configured), retrying once with a forced token refresh if the
+// upstream responds 401 Unauthorized and an OAuth2 TokenSource is in
+// use. It returns the bearer token that was ultimately accepted and its
+// expiry (the zero time for the static-token path).
+func callMkubeUpstream(client *http.Client, r *http.Request, staticToken string) (string, time.Time, *http.Response, error) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return "", time.Time{}, nil, err
+		}
+	}
+
+	bearer, expiry, err := mkubeUpstreamToken(staticToken)
+	if err != nil {
+		return "", time.Time{}, nil, err
+	}
+
+	resp, err := doMkubeRequest(client, r, bearer, body)
+	if err != nil {
+		return "", time.Time{}, nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && mkubeOAuth2TokenSource != nil {
+		resp.Body.Close()
+		invalidateMkubeUpstreamToken()
+
+		bearer, expiry, err = mkubeUpstreamToken(staticToken)
+		if err != nil {
+			return "", time.Time{}, nil, err
+		}
+		resp, err = doMkubeRequest(client, r, bearer, body)
+		if err != nil {
+			return "", time.Time{}, nil, err
+		}
+	}
+
+	return bearer, expiry, resp, nil
+}
+
+// doMkubeRequest issues a copy of r against the mkube upstream,
+// authenticated with the given bearer token. body is re-wrapped in a
+// fresh reader on every call so a 401 retry in callMkubeUpstream
+// resends the original request body instead of the now-drained one.
+func doMkubeRequest(client *http.Client, r *http.Request, bearer string, body []byte) (*http.Response, error) {
+	upstream, err := http.NewRequest(r.Method, r.URL.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	upstream.Header.Set("Authorization", fmt.Sprintf("Bearer %s", bearer))
+	return client.Do(upstream)
+}
+
+// bearerToken extracts the token from a "Bearer <token>" Authorization
+// header, or returns the empty string if the header is absent or
+// malformed.
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(h, prefix)
+}
+
+// jwtExpiry reads the "exp" claim out of tokenString without
+// re-verifying its signature, since the caller has already done so via
+// authenticateSessionJWT.
+func jwtExpiry(tokenString string) (time.Time, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(tokenString, claims); err != nil {
+		return time.Time{}, fmt.Errorf("unable to read token expiry: %w", err)
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return time.Time{}, fmt.Errorf("token is missing an exp claim")
+	}
+	return time.Unix(int64(exp), 0), nil
+}
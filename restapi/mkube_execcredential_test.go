@@ -0,0 +1,187 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/minio/mcs/pkg/auth"
+)
+
+func Test_serverMkubeExecCredential(t *testing.T) {
+	jwt, _ := auth.NewJWTWithClaimsForClient(creds, []string{""}, audience)
+
+	OKclient := NewTestClient(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`OK`)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	badClient := NewTestClient(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 500,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`NOTOK`)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	refusedClient := NewTestClient(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 500,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`NOTOK`)),
+			Header:     make(http.Header),
+		}, errors.New("connection refused")
+	})
+
+	testURL, _ := url.Parse("/api/v1/clusters")
+	dummyBody := ioutil.NopCloser(bytes.NewReader([]byte("foo")))
+
+	type args struct {
+		client   *http.Client
+		recorder *httptest.ResponseRecorder
+		req      *http.Request
+	}
+	tests := []struct {
+		name     string
+		args     args
+		wantCode int
+	}{
+		{
+			name: "Successful request",
+			args: args{
+				client:   OKclient,
+				recorder: httptest.NewRecorder(),
+				req: &http.Request{
+					Body: dummyBody,
+					URL:  testURL,
+					Header: http.Header{
+						"Authorization": []string{fmt.Sprintf("Bearer %s", jwt)},
+					},
+				},
+			},
+			wantCode: 200,
+		},
+		{
+			name: "Unsuccessful request - missing jwt",
+			args: args{
+				client:   OKclient,
+				recorder: httptest.NewRecorder(),
+				req: &http.Request{
+					Body:   dummyBody,
+					URL:    testURL,
+					Header: http.Header{},
+				},
+			},
+			wantCode: 500,
+		},
+		{
+			name: "Unsuccessful request - expired/invalid jwt",
+			args: args{
+				client:   OKclient,
+				recorder: httptest.NewRecorder(),
+				req: &http.Request{
+					Body: dummyBody,
+					URL:  testURL,
+					Header: http.Header{
+						"Authorization": []string{"Bearer not-a-valid-jwt"},
+					},
+				},
+			},
+			wantCode: 500,
+		},
+		{
+			name: "Unsuccessful request - upstream mkube 5xx",
+			args: args{
+				client:   badClient,
+				recorder: httptest.NewRecorder(),
+				req: &http.Request{
+					Body: dummyBody,
+					URL:  testURL,
+					Header: http.Header{
+						"Authorization": []string{fmt.Sprintf("Bearer %s", jwt)},
+					},
+				},
+			},
+			wantCode: 500,
+		},
+		{
+			name: "Unsuccessful request - upstream refused connection",
+			args: args{
+				client:   refusedClient,
+				recorder: httptest.NewRecorder(),
+				req: &http.Request{
+					Body: dummyBody,
+					URL:  testURL,
+					Header: http.Header{
+						"Authorization": []string{fmt.Sprintf("Bearer %s", jwt)},
+					},
+				},
+			},
+			wantCode: 500,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverMkubeExecCredential(tt.args.client, tt.args.recorder, tt.args.req)
+			resp := tt.args.recorder.Result()
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("Invalid code returned, expected: %d received: %d", tt.wantCode, resp.StatusCode)
+				return
+			}
+			if tt.wantCode != 200 {
+				return
+			}
+
+			var cred execCredential
+			if err := json.NewDecoder(resp.Body).Decode(&cred); err != nil {
+				t.Fatalf("unable to decode response body: %v", err)
+			}
+			if cred.APIVersion != "client.authentication.k8s.io/v1beta1" || cred.Kind != "ExecCredential" {
+				t.Errorf("unexpected ExecCredential envelope: %+v", cred)
+			}
+			if cred.Status.Token == "" {
+				t.Error("expected a non-empty status.token")
+			}
+			if _, err := time.Parse(time.RFC3339, cred.Status.ExpirationTimestamp); err != nil {
+				t.Errorf("expirationTimestamp is not RFC3339: %v", err)
+			}
+		})
+	}
+}
+
+func Test_IsExecCredentialRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/clusters", nil)
+	if IsExecCredentialRequest(req) {
+		t.Error("expected a request with no Accept header to not be an ExecCredential request")
+	}
+
+	req.Header.Set("Accept", execCredentialAccept)
+	if !IsExecCredentialRequest(req) {
+		t.Error("expected the ExecCredential Accept header to be recognized")
+	}
+}
@@ -0,0 +1,69 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"time"
+
+	"github.com/minio/mcs/pkg/auth/oauth2"
+)
+
+// mkubeOAuth2TokenSource is installed by ConfigureMkubeOAuth2 when the
+// Console is started with --mkube-oauth2-keyfile, and used in place of
+// the static service-account token derived from the caller's Console
+// JWT when talking to the mkube upstream.
+var mkubeOAuth2TokenSource *oauth2.TokenSource
+
+// ConfigureMkubeOAuth2 loads the OAuth2 client-credentials keyfile
+// named by the --mkube-oauth2-keyfile flag, if any, and installs it as
+// the source of bearer tokens used to reach the mkube upstream. It is
+// a no-op when keyfilePath is empty.
+func ConfigureMkubeOAuth2(keyfilePath string) error {
+	if keyfilePath == "" {
+		return nil
+	}
+	kf, err := oauth2.LoadKeyfile(keyfilePath)
+	if err != nil {
+		return err
+	}
+	mkubeOAuth2TokenSource = oauth2.NewTokenSource(kf, "")
+	return nil
+}
+
+// mkubeUpstreamToken returns the bearer token to present to the mkube
+// upstream, together with its real expiry: a freshly minted OAuth2
+// access token when --mkube-oauth2-keyfile is configured, otherwise
+// staticToken (the token embedded in the caller's Console JWT). The
+// returned expiry is the zero time for staticToken, since its expiry
+// is the caller's own Console session JWT's exp, which the caller
+// already has.
+func mkubeUpstreamToken(staticToken string) (string, time.Time, error) {
+	if mkubeOAuth2TokenSource == nil {
+		return staticToken, time.Time{}, nil
+	}
+	return mkubeOAuth2TokenSource.Token()
+}
+
+// invalidateMkubeUpstreamToken discards the cached OAuth2 access token,
+// if one is configured, so the next mkubeUpstreamToken call fetches a
+// fresh one. Call this after the mkube upstream rejects a request with
+// 401 Unauthorized.
+func invalidateMkubeUpstreamToken() {
+	if mkubeOAuth2TokenSource != nil {
+		mkubeOAuth2TokenSource.InvalidateToken()
+	}
+}
@@ -0,0 +1,225 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/minio/mcs/pkg/auth"
+)
+
+// newTestIDP starts a local HTTP server standing in for an OAuth2
+// issuer's discovery and token endpoints, returning an access token
+// that increments on every call.
+func newTestIDP(t *testing.T) *httptest.Server {
+	t.Helper()
+	requests := 0
+	var mux http.ServeMux
+	var srv *httptest.Server
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"token_endpoint": srv.URL + "/oauth2/token"})
+	})
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, `{"access_token":"idp-tok-%d","expires_in":3600}`, requests)
+	})
+	srv = httptest.NewServer(&mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func writeTestKeyfile(t *testing.T, issuerURL string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "keyfile.json")
+	data, _ := json.Marshal(map[string]string{
+		"type":          "client_credentials",
+		"client_id":     "client-1",
+		"client_secret": "secret-1",
+		"issuer_url":    issuerURL,
+		"audience":      "https://k8s.example.com",
+	})
+	if err := ioutil.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("unable to write keyfile: %v", err)
+	}
+	return path
+}
+
+// withMkubeOAuth2 configures mkubeOAuth2TokenSource for the duration of
+// the test, restoring it to nil afterwards.
+func withMkubeOAuth2(t *testing.T, keyfilePath string) {
+	t.Helper()
+	if err := ConfigureMkubeOAuth2(keyfilePath); err != nil {
+		t.Fatalf("unable to configure mkube oauth2: %v", err)
+	}
+	t.Cleanup(func() { mkubeOAuth2TokenSource = nil })
+}
+
+func Test_ConfigureMkubeOAuth2_disabledByDefault(t *testing.T) {
+	tok, expiry, err := mkubeUpstreamToken("static-token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tok != "static-token" {
+		t.Errorf("expected the static token when no keyfile is configured, got %q", tok)
+	}
+	if !expiry.IsZero() {
+		t.Errorf("expected a zero expiry for the static token, got %v", expiry)
+	}
+}
+
+func Test_ConfigureMkubeOAuth2_usesOAuth2Token(t *testing.T) {
+	idp := newTestIDP(t)
+	withMkubeOAuth2(t, writeTestKeyfile(t, idp.URL))
+
+	before := time.Now()
+	tok, expiry, err := mkubeUpstreamToken("static-token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tok != "idp-tok-1" {
+		t.Errorf("expected the OAuth2 token to override the static token, got %q", tok)
+	}
+	if expiry.Before(before.Add(55*time.Minute)) || expiry.After(before.Add(65*time.Minute)) {
+		t.Errorf("expected the OAuth2 token's own ~1h expiry, got %v", expiry)
+	}
+}
+
+func Test_serverMkubeExecCredential_retriesOnceOn401(t *testing.T) {
+	idp := newTestIDP(t)
+	withMkubeOAuth2(t, writeTestKeyfile(t, idp.URL))
+
+	jwt, _ := auth.NewJWTWithClaimsForClient(creds, []string{""}, audience)
+
+	calls := 0
+	client := NewTestClient(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return &http.Response{
+				StatusCode: http.StatusUnauthorized,
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`unauthorized`)),
+				Header:     make(http.Header),
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`OK`)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	testURL, _ := url.Parse("/api/v1/clusters")
+	req := &http.Request{
+		Method: http.MethodGet,
+		URL:    testURL,
+		Header: http.Header{"Authorization": []string{fmt.Sprintf("Bearer %s", jwt)}},
+	}
+	recorder := httptest.NewRecorder()
+
+	serverMkubeExecCredential(client, recorder, req)
+
+	resp := recorder.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the retry to succeed, got status %d", resp.StatusCode)
+	}
+	if calls != 2 {
+		t.Errorf("expected exactly 2 upstream calls (one 401, one retry), got %d", calls)
+	}
+
+	var cred execCredential
+	if err := json.NewDecoder(resp.Body).Decode(&cred); err != nil {
+		t.Fatalf("unable to decode response body: %v", err)
+	}
+	if cred.Status.Token != "idp-tok-2" {
+		t.Errorf("expected the refreshed OAuth2 token, got %q", cred.Status.Token)
+	}
+
+	// ExpirationTimestamp must track the refreshed OAuth2 bearer's own
+	// ~1h expires_in, not the (much longer-lived) Console session JWT's
+	// exp - otherwise kubectl keeps presenting an expired bearer to the
+	// k8s API server well after the OAuth2 token has actually expired.
+	expiration, err := time.Parse(time.RFC3339, cred.Status.ExpirationTimestamp)
+	if err != nil {
+		t.Fatalf("unable to parse ExpirationTimestamp: %v", err)
+	}
+	if until := time.Until(expiration); until < 55*time.Minute || until > 65*time.Minute {
+		t.Errorf("expected ExpirationTimestamp to reflect the OAuth2 token's own ~1h expiry, got %v from now", until)
+	}
+}
+
+// Test_serverMkubeExecCredential_retryResendsBody guards against a
+// regression where the retry-on-401 path forwarded the same, already
+// drained request body reader to the upstream a second time, silently
+// sending an empty body instead of the original one.
+func Test_serverMkubeExecCredential_retryResendsBody(t *testing.T) {
+	idp := newTestIDP(t)
+	withMkubeOAuth2(t, writeTestKeyfile(t, idp.URL))
+
+	jwt, _ := auth.NewJWTWithClaimsForClient(creds, []string{""}, audience)
+
+	var bodies []string
+	calls := 0
+	client := NewTestClient(func(req *http.Request) (*http.Response, error) {
+		calls++
+		b, _ := ioutil.ReadAll(req.Body)
+		bodies = append(bodies, string(b))
+		if calls == 1 {
+			return &http.Response{
+				StatusCode: http.StatusUnauthorized,
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`unauthorized`)),
+				Header:     make(http.Header),
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`OK`)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	testURL, _ := url.Parse("/api/v1/clusters")
+	req := &http.Request{
+		Method: http.MethodPost,
+		URL:    testURL,
+		Body:   ioutil.NopCloser(bytes.NewBufferString(`{"original":"payload"}`)),
+		Header: http.Header{"Authorization": []string{fmt.Sprintf("Bearer %s", jwt)}},
+	}
+	recorder := httptest.NewRecorder()
+
+	serverMkubeExecCredential(client, recorder, req)
+
+	if resp := recorder.Result(); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the retry to succeed, got status %d", resp.StatusCode)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("expected exactly 2 upstream calls, got %d", len(bodies))
+	}
+	for i, b := range bodies {
+		if b != `{"original":"payload"}` {
+			t.Errorf("call %d: expected the original request body to be forwarded, got %q", i+1, b)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/minio/mcs/pkg/auth"
+	"github.com/minio/minio-go/v6/pkg/credentials"
+)
+
+// sessionJWTTTL bounds how long a Console session JWT minted by
+// issueSessionJWT is valid for.
+const sessionJWTTTL = 12 * time.Hour
+
+// sessionClaims is the claim set issueSessionJWT signs and
+// authenticateSessionJWT verifies, using the same space-delimited
+// "scope" claim convention serverRotateSigningKey already expects from
+// adminClaims.
+type sessionClaims struct {
+	AccessKeyID  string `json:"accessKey"`
+	SessionToken string `json:"sessionToken"`
+	Scope        string `json:"scope"`
+	jwt.StandardClaims
+}
+
+// issueSessionJWT mints the Console session JWT handed back to the
+// caller once a login flow (OIDC or a connector) completes. When
+// signingKeys is configured it signs with the rotating ring, so the
+// same token verifies against /.well-known/jwks.json and the admin
+// "rotate signing keys" API can recognize an admin-scoped session;
+// otherwise it falls back to auth.NewJWTWithClaimsForClient, exactly
+// as before signing key rotation existed.
+func issueSessionJWT(creds *credentials.Value, scopes []string, audience string) (string, error) {
+	if signingKeys == nil {
+		return auth.NewJWTWithClaimsForClient(creds, scopes, audience)
+	}
+	claims := sessionClaims{
+		AccessKeyID:  creds.AccessKeyID,
+		SessionToken: creds.SessionToken,
+		Scope:        strings.Join(scopes, " "),
+		StandardClaims: jwt.StandardClaims{
+			Subject:   creds.AccessKeyID,
+			Audience:  audience,
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: time.Now().Add(sessionJWTTTL).Unix(),
+		},
+	}
+	tokenString, err := signingKeys.Sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("restapi: unable to sign session token: %w", err)
+	}
+	return tokenString, nil
+}
+
+// authenticateSessionJWT verifies tokenString and returns the
+// credentials.Value it carries. It tries signingKeys first, since that
+// is what issueSessionJWT signs with whenever signing key rotation is
+// configured, and falls back to auth.JWTAuthenticate so tokens minted
+// before signing keys were configured - or while they're disabled -
+// keep validating.
+func authenticateSessionJWT(tokenString string) (*credentials.Value, error) {
+	if signingKeys != nil {
+		var claims sessionClaims
+		if err := signingKeys.Verify(tokenString, &claims); err == nil {
+			return &credentials.Value{AccessKeyID: claims.AccessKeyID, SessionToken: claims.SessionToken}, nil
+		}
+	}
+	return auth.JWTAuthenticate(tokenString)
+}
@@ -0,0 +1,58 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"testing"
+
+	"github.com/minio/minio-go/v6/pkg/credentials"
+)
+
+func Test_issueSessionJWT_authenticateSessionJWT_roundTrip(t *testing.T) {
+	withSigningKeys(t)
+
+	creds := &credentials.Value{AccessKeyID: "alice", SessionToken: "upstream-token"}
+	tokenString, err := issueSessionJWT(creds, []string{"openid", "admin"}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := authenticateSessionJWT(tokenString)
+	if err != nil {
+		t.Fatalf("expected a session JWT minted by issueSessionJWT to authenticate, got: %v", err)
+	}
+	if got.AccessKeyID != "alice" || got.SessionToken != "upstream-token" {
+		t.Errorf("unexpected credentials: %+v", got)
+	}
+}
+
+func Test_issueSessionJWT_signsWithSigningKeysWhenConfigured(t *testing.T) {
+	withSigningKeys(t)
+
+	tokenString, err := issueSessionJWT(&credentials.Value{AccessKeyID: "alice"}, []string{"openid"}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var claims sessionClaims
+	if err := signingKeys.Verify(tokenString, &claims); err != nil {
+		t.Fatalf("expected the session JWT to verify against signingKeys, got: %v", err)
+	}
+	if claims.AccessKeyID != "alice" || claims.Scope != "openid" {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+}
@@ -0,0 +1,50 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/minio/mcs/pkg/auth/keys"
+)
+
+// signingKeys is the rotating ring of RSA keys used to sign and verify
+// Console-issued JWTs, replacing a single long-lived signing key.
+// issueSessionJWT and authenticateSessionJWT delegate to it once
+// installed, falling back to the legacy auth package only when it is
+// nil.
+var signingKeys *keys.KeyRing
+
+// ConfigureSigningKeys installs ring as the Console's signing key ring
+// and starts its background rotation loop.
+func ConfigureSigningKeys(ring *keys.KeyRing) {
+	signingKeys = ring
+	signingKeys.Start()
+}
+
+// serverJWKS serves the ring's current public keys at
+// /.well-known/jwks.json in standard JWK form, so third-party services
+// can verify Console-issued JWTs without sharing a secret.
+func serverJWKS(w http.ResponseWriter, r *http.Request) {
+	if signingKeys == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(signingKeys.JWKS())
+}
@@ -0,0 +1,72 @@
+// This file is part of MinIO Console Server
+// Copyright (c) 2020 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/minio/mcs/pkg/auth/keys"
+)
+
+// withSigningKeys installs a fresh KeyRing for the duration of the
+// test, without starting its background rotation goroutine, and resets
+// the package state afterwards.
+func withSigningKeys(t *testing.T) *keys.KeyRing {
+	t.Helper()
+	ring, err := keys.NewKeyRing(time.Hour)
+	if err != nil {
+		t.Fatalf("unable to create key ring: %v", err)
+	}
+	signingKeys = ring
+	t.Cleanup(func() { signingKeys = nil })
+	return ring
+}
+
+func Test_serverJWKS_notConfigured(t *testing.T) {
+	signingKeys = nil
+	recorder := httptest.NewRecorder()
+	serverJWKS(recorder, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	if recorder.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 when no signing keys are configured, got %d", recorder.Result().StatusCode)
+	}
+}
+
+func Test_serverJWKS(t *testing.T) {
+	ring := withSigningKeys(t)
+	kid, _ := ring.Primary()
+
+	recorder := httptest.NewRecorder()
+	serverJWKS(recorder, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	resp := recorder.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var set keys.JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		t.Fatalf("unable to decode response body: %v", err)
+	}
+	if len(set.Keys) != 1 || set.Keys[0].Kid != kid {
+		t.Errorf("unexpected JWKS body: %+v", set)
+	}
+}